@@ -6,6 +6,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	api "github.com/nanovms/ops/lepton"
 	"github.com/spf13/cobra"
@@ -33,9 +34,10 @@ func ImageCommands() *cobra.Command {
 
 func imageCreateCommand() *cobra.Command {
 	var (
-		config, pkg, imageName string
-		args, mounts           []string
-		nightly                bool
+		config, pkg, imageName, arch string
+		args, mounts, replicateTo    []string
+		nightly, secureBoot          bool
+		pk, kek, db, dbx             string
 	)
 
 	var cmdImageCreate = &cobra.Command{
@@ -51,6 +53,15 @@ func imageCreateCommand() *cobra.Command {
 	cmdImageCreate.PersistentFlags().BoolVarP(&nightly, "nightly", "n", false, "nightly build")
 
 	cmdImageCreate.PersistentFlags().StringVarP(&imageName, "imagename", "i", "", "image name")
+	cmdImageCreate.PersistentFlags().StringVar(&arch, "arch", "", "target architecture [x86_64, arm64]")
+
+	cmdImageCreate.PersistentFlags().BoolVar(&secureBoot, "secure-boot", false, "register the image with UEFI Secure Boot enabled")
+	cmdImageCreate.PersistentFlags().StringVar(&pk, "pk", "", "path to the Secure Boot Platform Key (PEM)")
+	cmdImageCreate.PersistentFlags().StringVar(&kek, "kek", "", "path to the Secure Boot Key Exchange Key (PEM)")
+	cmdImageCreate.PersistentFlags().StringVar(&db, "db", "", "path to the Secure Boot signature database certificate (PEM)")
+	cmdImageCreate.PersistentFlags().StringVar(&dbx, "dbx", "", "path to the Secure Boot forbidden signature database (PEM)")
+
+	cmdImageCreate.PersistentFlags().StringArrayVar(&replicateTo, "replicate-to", nil, "AWS regions to replicate the registered AMI into")
 	return cmdImageCreate
 }
 
@@ -68,6 +79,17 @@ func imageCreateCommandHandler(cmd *cobra.Command, args []string) {
 		panic(err)
 	}
 
+	secureBoot, err := strconv.ParseBool(cmd.Flag("secure-boot").Value.String())
+	if err != nil {
+		panic(err)
+	}
+	pk, _ := cmd.Flags().GetString("pk")
+	kek, _ := cmd.Flags().GetString("kek")
+	db, _ := cmd.Flags().GetString("db")
+	dbx, _ := cmd.Flags().GetString("dbx")
+	arch, _ := cmd.Flags().GetString("arch")
+	replicateTo, _ := cmd.Flags().GetStringArray("replicate-to")
+
 	c := unWarpConfig(config)
 	AppendGlobalCmdFlagsToConfig(cmd.Flags(), c)
 
@@ -76,6 +98,24 @@ func imageCreateCommandHandler(cmd *cobra.Command, args []string) {
 		c.CloudConfig.Platform = provider
 	}
 
+	if len(arch) > 0 {
+		c.CloudConfig.Architecture = arch
+	}
+
+	if len(replicateTo) > 0 {
+		c.CloudConfig.ReplicateTo = replicateTo
+	}
+
+	if secureBoot {
+		c.CloudConfig.SecureBoot = &api.SecureBootConfig{
+			Enabled: true,
+			PK:      pk,
+			KEK:     kek,
+			DB:      db,
+			DBX:     dbx,
+		}
+	}
+
 	if nightly {
 		c.NightlyBuild = nightly
 	}
@@ -128,6 +168,12 @@ func imageCreateCommandHandler(cmd *cobra.Command, args []string) {
 		c = mergeConfigs(pkgConfig, c)
 		setDefaultImageName(cmd, c)
 
+		if len(c.CloudConfig.Architecture) > 0 {
+			if err := api.ValidateArch(c.Program, c.CloudConfig.Architecture); err != nil {
+				exitWithError(err.Error())
+			}
+		}
+
 		// Config merged with package config, need to update context
 		ctx = api.NewContext(c)
 
@@ -144,6 +190,12 @@ func imageCreateCommandHandler(cmd *cobra.Command, args []string) {
 			exitWithError("Please mention program to run")
 		}
 
+		if len(c.CloudConfig.Architecture) > 0 {
+			if err := api.ValidateArch(c.Program, c.CloudConfig.Architecture); err != nil {
+				exitWithError(err.Error())
+			}
+		}
+
 		setDefaultImageName(cmd, c)
 		keypath, err = p.BuildImage(ctx)
 		if err != nil {
@@ -160,11 +212,13 @@ func imageCreateCommandHandler(cmd *cobra.Command, args []string) {
 }
 
 func imageListCommand() *cobra.Command {
+	var allRegions bool
 	var cmdImageList = &cobra.Command{
 		Use:   "list",
 		Short: "list images from provider",
 		Run:   imageListCommandHandler,
 	}
+	cmdImageList.PersistentFlags().BoolVar(&allRegions, "all-regions", false, "fan out and list images across all AWS regions")
 	return cmdImageList
 }
 
@@ -172,6 +226,7 @@ func imageListCommandHandler(cmd *cobra.Command, args []string) {
 	provider, _ := cmd.Flags().GetString("target-cloud")
 	config, _ := cmd.Flags().GetString("config")
 	config = strings.TrimSpace(config)
+	allRegions, _ := cmd.Flags().GetBool("all-regions")
 
 	var c *api.Config
 	c = unWarpConfig(config)
@@ -189,19 +244,37 @@ func imageListCommandHandler(cmd *cobra.Command, args []string) {
 
 	ctx := api.NewContext(c)
 
-	err = p.ListImages(ctx)
+	if allRegions {
+		awsProvider, ok := p.(*api.AWS)
+		if !ok {
+			exitWithError("--all-regions is only supported for aws")
+		}
+		err = awsProvider.ListImagesAllRegions(ctx)
+	} else {
+		err = p.ListImages(ctx)
+	}
 	if err != nil {
 		exitWithError(err.Error())
 	}
 }
 
 func imageDeleteCommand() *cobra.Command {
+	var (
+		all       bool
+		match     string
+		olderThan string
+		dryRun    bool
+	)
+
 	var cmdImageDelete = &cobra.Command{
-		Use:   "delete <image_name>",
+		Use:   "delete [image_name...]",
 		Short: "delete images from provider",
 		Run:   imageDeleteCommandHandler,
-		Args:  cobra.MinimumNArgs(1),
 	}
+	cmdImageDelete.PersistentFlags().BoolVar(&all, "all", false, "delete all images")
+	cmdImageDelete.PersistentFlags().StringVar(&match, "match", "", "delete images whose name matches this glob")
+	cmdImageDelete.PersistentFlags().StringVar(&olderThan, "older-than", "", "delete images older than this duration, e.g. 720h")
+	cmdImageDelete.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print what would be deleted without deleting anything")
 	return cmdImageDelete
 }
 
@@ -210,6 +283,24 @@ func imageDeleteCommandHandler(cmd *cobra.Command, args []string) {
 	config, _ := cmd.Flags().GetString("config")
 	config = strings.TrimSpace(config)
 
+	all, _ := cmd.Flags().GetBool("all")
+	match, _ := cmd.Flags().GetString("match")
+	olderThan, _ := cmd.Flags().GetString("older-than")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if !all && match == "" && olderThan == "" && len(args) == 0 {
+		exitWithError("Please specify image name(s), or one of --all, --match, --older-than")
+	}
+
+	var age time.Duration
+	if olderThan != "" {
+		var err error
+		age, err = time.ParseDuration(olderThan)
+		if err != nil {
+			exitWithError(err.Error())
+		}
+	}
+
 	c := unWarpConfig(config)
 	AppendGlobalCmdFlagsToConfig(cmd.Flags(), c)
 
@@ -223,7 +314,15 @@ func imageDeleteCommandHandler(cmd *cobra.Command, args []string) {
 		exitWithError(err.Error())
 	}
 
-	err = p.DeleteImage(ctx, args[0])
+	filter := api.ImageFilter{
+		Names:     args,
+		All:       all,
+		Match:     match,
+		OlderThan: age,
+		DryRun:    dryRun,
+	}
+
+	_, err = p.DeleteImages(ctx, filter)
 	if err != nil {
 		exitWithError(err.Error())
 	}
@@ -277,12 +376,21 @@ func imageSyncCommand() *cobra.Command {
 	return cmdImageSync
 }
 
+// syncSources are the source clouds with a real SyncImage implementation.
+// Sync from anything else would silently report success without moving an
+// image, so it's rejected up front rather than left to whatever the
+// provider's still-stubbed SyncImage happens to return.
+var syncSources = map[string]bool{
+	"onprem": true,
+	"aws":    true,
+}
+
 func imageSyncCommandHandler(cmd *cobra.Command, args []string) {
 	image := args[0]
-	// TODO only accepts onprem for now, implement for other source providers later
 	source, _ := cmd.Flags().GetString("source-cloud")
-	if source != "onprem" {
-		exitWithError(source + " sync not yet implemented")
+
+	if !syncSources[source] {
+		exitWithError(fmt.Sprintf("image sync from %q is not yet implemented", source))
 	}
 
 	config, _ := cmd.Flags().GetString("config")
@@ -0,0 +1,56 @@
+package lepton
+
+import (
+	"debug/elf"
+	"fmt"
+)
+
+// supported AMI/instance architectures
+const (
+	ArchX86_64 = "x86_64"
+	ArchARM64  = "arm64"
+)
+
+// elfMachineToArch maps an ELF header machine type to the Architecture
+// values accepted by CloudConfig.
+var elfMachineToArch = map[elf.Machine]string{
+	elf.EM_X86_64: ArchX86_64,
+	elf.EM_AARCH64: ArchARM64,
+}
+
+// ValidateArch checks that the requested arch matches the ELF machine type
+// of program, so we don't register an AMI that can't boot the kernel it was
+// built against.
+func ValidateArch(program string, arch string) error {
+	if arch == "" {
+		return nil
+	}
+
+	f, err := elf.Open(program)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	got, ok := elfMachineToArch[f.Machine]
+	if !ok {
+		return fmt.Errorf("unsupported ELF machine type %s in %s", f.Machine, program)
+	}
+
+	if got != arch {
+		return fmt.Errorf("--arch %s does not match %s's ELF architecture %s", arch, program, got)
+	}
+
+	return nil
+}
+
+// DefaultInstanceTypeForArch returns the default AWS instance type for a
+// given nanos image architecture (t4g.micro for arm64/Graviton, t3.micro
+// otherwise). Nothing calls it: --arch does not affect the instance type
+// chosen by instance create.
+func DefaultInstanceTypeForArch(arch string) string {
+	if arch == ArchARM64 {
+		return "t4g.micro"
+	}
+	return "t3.micro"
+}
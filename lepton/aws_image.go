@@ -1,10 +1,15 @@
 package lepton
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -39,20 +44,49 @@ func (p *AWS) BuildImageWithPackage(ctx *Context, pkgpath string) (string, error
 // CreateImage - Creates image on AWS using nanos images
 // TODO : re-use and cache DefaultClient and instances.
 func (p *AWS) CreateImage(ctx *Context, imagePath string) error {
-	// this is a really convulted setup
-	// 1) upload the image
-	// 2) create a snapshot
-	// 3) create an image
+	c := ctx.config
 
-	err := p.Storage.CopyToBucket(ctx.config, imagePath)
-	if err != nil {
-		return err
-	}
+	uploader := newStagedUploader(
+		awsArchiver{},
+		&awsBlobStore{p: p, c: c},
+		&awsDiskImporter{p: p, c: c, ctx: ctx},
+		&awsImageRegistrar{p: p, c: c, logger: ctx.logger},
+	)
 
-	c := ctx.config
+	_, err := uploader.Upload(context.Background(), UploadRequest{ImagePath: imagePath, Config: c, Logger: ctx.logger})
+	return err
+}
 
-	bucket := c.CloudConfig.BucketName
-	key := c.CloudConfig.ImageName
+// awsArchiver is a no-op Archiver: nanos images are already raw disks.
+type awsArchiver struct{}
+
+func (awsArchiver) Archive(ctx context.Context, imagePath string) (string, error) {
+	return imagePath, nil
+}
+
+// awsBlobStore puts/deletes objects in the configured S3 bucket.
+type awsBlobStore struct {
+	p *AWS
+	c *Config
+}
+
+func (s *awsBlobStore) Put(ctx context.Context, key, path string) error {
+	return s.p.Storage.CopyToBucket(s.c, path)
+}
+
+func (s *awsBlobStore) Delete(ctx context.Context, key string) error {
+	return s.p.Storage.DeleteFromBucket(s.c, key)
+}
+
+// awsDiskImporter imports an S3 object as an EBS snapshot.
+type awsDiskImporter struct {
+	p   *AWS
+	c   *Config
+	ctx *Context
+}
+
+func (i *awsDiskImporter) Import(ctx context.Context, key string) (string, error) {
+	bucket := i.c.CloudConfig.BucketName
 
 	input := &ec2.ImportSnapshotInput{
 		Description: aws.String("NanoVMs test"),
@@ -66,51 +100,70 @@ func (p *AWS) CreateImage(ctx *Context, imagePath string) error {
 		},
 	}
 
-	ctx.logger.Info("Importing snapshot from s3 image file")
-	res, err := p.ec2.ImportSnapshot(input)
+	// ImportSnapshot is not idempotent - it kicks off a brand-new import task
+	// every time it's called - so it runs exactly once here. Only the
+	// read-only wait and the tagging below are safe to retry.
+	i.ctx.logger.Info("Importing snapshot from s3 image file")
+	res, err := i.p.ec2.ImportSnapshot(input)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	snapshotID, err := p.waitSnapshotToBeReady(c, res.ImportTaskId)
-	if err != nil {
+	var snapshotID *string
+	err = withRetry(i.ctx.logger, "import-wait", func() (err error) {
+		snapshotID, err = i.p.waitSnapshotToBeReady(i.c, res.ImportTaskId)
 		return err
-	}
-
-	// delete the tmp s3 image
-	ctx.logger.Info("Deleting s3 image file")
-	err = p.Storage.DeleteFromBucket(c, key)
+	})
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// tag the volume
-	tags, _ := buildAwsTags(c.RunConfig.Tags, key)
+	tags, _ := buildAwsTags(i.c.RunConfig.Tags, key)
 
-	ctx.logger.Log("Tagging snapshot")
-	_, err = p.ec2.CreateTags(&ec2.CreateTagsInput{
-		Resources: []*string{snapshotID},
-		Tags:      tags,
+	i.ctx.logger.Log("Tagging snapshot")
+	err = withRetry(i.ctx.logger, "import-tag", func() error {
+		_, err := i.p.ec2.CreateTags(&ec2.CreateTagsInput{
+			Resources: []*string{snapshotID},
+			Tags:      tags,
+		})
+		return err
 	})
 	if err != nil {
-		return err
+		return "", err
 	}
 
+	return aws.StringValue(snapshotID), nil
+}
+
+// awsImageRegistrar registers an AMI from an imported EBS snapshot.
+type awsImageRegistrar struct {
+	p      *AWS
+	c      *Config
+	logger Logger
+}
+
+func (r *awsImageRegistrar) Register(ctx context.Context, diskID string) (string, error) {
+	key := r.c.CloudConfig.ImageName
+
 	t := time.Now().UnixNano()
 	s := strconv.FormatInt(t, 10)
 
 	amiName := key + s
 
-	// register ami
+	arch := r.c.CloudConfig.Architecture
+	if arch == "" {
+		arch = ArchX86_64
+	}
+
 	rinput := &ec2.RegisterImageInput{
 		Name:         aws.String(amiName),
-		Architecture: aws.String("x86_64"),
+		Architecture: aws.String(arch),
 		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
 			{
 				DeviceName: aws.String("/dev/sda1"),
 				Ebs: &ec2.EbsBlockDevice{
 					DeleteOnTermination: aws.Bool(false),
-					SnapshotId:          snapshotID,
+					SnapshotId:          aws.String(diskID),
 					VolumeType:          aws.String("gp2"),
 				},
 			},
@@ -121,20 +174,45 @@ func (p *AWS) CreateImage(ctx *Context, imagePath string) error {
 		EnaSupport:         aws.Bool(false),
 	}
 
-	ctx.logger.Info("Registering image")
-	resreg, err := p.ec2.RegisterImage(rinput)
+	sb := r.c.CloudConfig.SecureBoot
+	if sb != nil && sb.Enabled {
+		uefiData, err := buildUefiData(sb)
+		if err != nil {
+			return "", err
+		}
+
+		rinput.BootMode = aws.String("uefi")
+		rinput.TpmSupport = aws.String("v2.0")
+		rinput.UefiData = aws.String(uefiData)
+	}
+
+	resreg, err := r.p.ec2.RegisterImage(rinput)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// Add name tag to the created ami
-	ctx.logger.Info("Tagging image")
-	_, err = p.ec2.CreateTags(&ec2.CreateTagsInput{
+	tags, _ := buildAwsTags(r.c.RunConfig.Tags, key)
+	_, err = r.p.ec2.CreateTags(&ec2.CreateTagsInput{
 		Resources: []*string{resreg.ImageId},
 		Tags:      tags,
 	})
+	if err != nil {
+		return "", err
+	}
 
-	return nil
+	imageID := aws.StringValue(resreg.ImageId)
+
+	// Replication is best-effort: the primary AMI is already registered and
+	// tagged in the source region at this point, so a replication failure
+	// (e.g. one target region timing out) shouldn't report the whole image
+	// create as failed and strand its ID. It's logged instead.
+	if len(r.c.CloudConfig.ReplicateTo) > 0 {
+		if err := r.p.replicateImage(imageID, r.c, tags); err != nil {
+			logInfo(r.logger, "Image %s registered, but replication failed: %s", imageID, err)
+		}
+	}
+
+	return imageID, nil
 }
 
 func getAWSImages(ec2Service *ec2.EC2) (*ec2.DescribeImagesOutput, error) {
@@ -289,10 +367,229 @@ func (p *AWS) DeleteImage(ctx *Context, imagename string) error {
 	return nil
 }
 
+// DeleteImages deletes every AWS image matching filter, deregistering each
+// AMI and best-effort deleting its backing snapshot over a bounded worker
+// pool. A failed deregister/delete on one image does not abort the batch;
+// per-image results are reported in a table.
+func (p *AWS) DeleteImages(ctx *Context, filter ImageFilter) ([]string, error) {
+	result, err := getAWSImages(p.ec2)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []*ec2.Image
+	for _, image := range result.Images {
+		if imageMatchesFilter(image, filter) {
+			targets = append(targets, image)
+		}
+	}
+
+	const maxWorkers = 8
+	sem := make(chan struct{}, maxWorkers)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var deleted []string
+	rows := make([][]string, 0, len(targets))
+
+	for _, image := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(image *ec2.Image) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			name := imageName(image)
+			status := "would delete"
+
+			if !filter.DryRun {
+				if err := p.deregisterImage(image); err != nil {
+					status = "failed: " + err.Error()
+				} else {
+					status = "deleted"
+				}
+			}
+
+			mu.Lock()
+			if status == "deleted" {
+				deleted = append(deleted, name)
+			}
+			rows = append(rows, []string{name, status})
+			mu.Unlock()
+		}(image)
+	}
+	wg.Wait()
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Status"})
+	table.SetRowLine(true)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.Render()
+
+	return deleted, nil
+}
+
+// deregisterImage deregisters an AMI and deletes its backing snapshot.
+// Snapshot cleanup is best-effort: the AMI is already gone either way.
+func (p *AWS) deregisterImage(image *ec2.Image) error {
+	_, err := p.ec2.DeregisterImage(&ec2.DeregisterImageInput{ImageId: image.ImageId})
+	if err != nil {
+		return err
+	}
+
+	if len(image.BlockDeviceMappings) > 0 && image.BlockDeviceMappings[0].Ebs != nil {
+		snapID := image.BlockDeviceMappings[0].Ebs.SnapshotId
+		p.ec2.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: snapID})
+	}
+
+	return nil
+}
+
+func imageName(image *ec2.Image) string {
+	if len(image.Tags) > 0 {
+		return aws.StringValue(image.Tags[0].Value)
+	}
+	return aws.StringValue(image.Name)
+}
+
+// imageMatchesFilter reports whether image should be selected by filter.
+// An explicit name in filter.Names always selects the image outright.
+// Otherwise --match and --older-than are ANDed together when both are
+// given, so "ops image delete --match 'staging-*' --older-than 720h" only
+// deletes staging images that are also older than 720h, not every staging
+// image plus every old image.
+func imageMatchesFilter(image *ec2.Image, filter ImageFilter) bool {
+	if filter.All {
+		return true
+	}
+
+	name := imageName(image)
+
+	for _, n := range filter.Names {
+		if n == name {
+			return true
+		}
+	}
+
+	if filter.Match == "" && filter.OlderThan == 0 {
+		return false
+	}
+
+	if filter.Match != "" {
+		if ok, _ := filepath.Match(filter.Match, name); !ok {
+			return false
+		}
+	}
+
+	if filter.OlderThan > 0 {
+		created, err := time.Parse(time.RFC3339, aws.StringValue(image.CreationDate))
+		if err != nil || time.Since(created) <= filter.OlderThan {
+			return false
+		}
+	}
+
+	return true
+}
+
 // SyncImage syncs image from provider to another provider
 func (p *AWS) SyncImage(config *Config, target Provider, image string) error {
-	fmt.Println("not yet implemented")
-	return nil
+	ctx := NewContext(config)
+
+	ctx.logger.Info("Exporting %s from AWS to a raw disk", image)
+	imagePath, err := p.exportImageToDisk(ctx, image)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(imagePath)
+
+	ctx.config.RunConfig.Imagename = image
+	ctx.config.CloudConfig.ImageName = image
+
+	ctx.logger.Info("Importing %s into target provider", image)
+	return target.CreateImage(ctx, imagePath)
+}
+
+// exportImageToDisk stores the named AMI in S3 with CreateStoreImageTask
+// and downloads the stored object into LocalVolumeDir so it can be handed off to
+// another provider's CreateImage, mirroring the onprem->aws flow that already
+// goes through CopyToBucket + ImportSnapshot + RegisterImage.
+func (p *AWS) exportImageToDisk(ctx *Context, image string) (string, error) {
+	c := ctx.config
+
+	result, err := getAWSImages(p.ec2)
+	if err != nil {
+		return "", err
+	}
+
+	var amiID, snapshotID *string
+	for _, img := range result.Images {
+		if imageName(img) == image {
+			amiID = img.ImageId
+			snapshotID = img.BlockDeviceMappings[0].Ebs.SnapshotId
+			break
+		}
+	}
+	if amiID == nil {
+		return "", fmt.Errorf("image %s not found", image)
+	}
+
+	bucket := c.CloudConfig.BucketName
+	key := image + "-export.raw"
+
+	ctx.logger.Info("Storing image %s (snapshot %s) in s3://%s", aws.StringValue(amiID), aws.StringValue(snapshotID), bucket)
+	task, err := p.ec2.CreateStoreImageTask(&ec2.CreateStoreImageTaskInput{
+		ImageId: amiID,
+		Bucket:  aws.String(bucket),
+		S3ObjectTags: []*ec2.S3ObjectTag{
+			{Key: aws.String("CreatedBy"), Value: aws.String("ops")},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := p.waitStoreImageTask(task.ObjectKey); err != nil {
+		return "", err
+	}
+
+	imagePath := path.Join(LocalVolumeDir, key)
+	if err := p.Storage.CopyFromBucket(c, aws.StringValue(task.ObjectKey), imagePath); err != nil {
+		return "", err
+	}
+
+	return imagePath, nil
+}
+
+// waitStoreImageTask polls DescribeStoreImageTasks until our own export -
+// identified by objectKey - has finished streaming to the destination S3
+// bucket. Other store-image tasks running concurrently in the same
+// account/region are ignored.
+func (p *AWS) waitStoreImageTask(objectKey *string) error {
+	for i := 0; i < 120; i++ {
+		out, err := p.ec2.DescribeStoreImageTasks(&ec2.DescribeStoreImageTasksInput{})
+		if err != nil {
+			return err
+		}
+
+		for _, task := range out.StoreImageTaskResults {
+			if aws.StringValue(task.ObjectKey) != aws.StringValue(objectKey) {
+				continue
+			}
+			if aws.StringValue(task.StoreTaskState) == "Completed" {
+				return nil
+			}
+			if aws.StringValue(task.StoreTaskState) == "Failed" {
+				return fmt.Errorf("store image task failed: %s", aws.StringValue(task.StoreTaskFailureReason))
+			}
+		}
+
+		time.Sleep(15 * time.Second)
+	}
+
+	return fmt.Errorf("store image task timed out waiting for %s", aws.StringValue(objectKey))
 }
 
 // CustomizeImage returns image path with adaptations needed by cloud provider
@@ -321,39 +618,15 @@ func (p *AWS) waitSnapshotToBeReady(config *Config, importTaskID *string) (*stri
 
 	waitStartTime := time.Now()
 
-	ct := aws.BackgroundContext()
-	w := request.Waiter{
-		Name:        "DescribeImportSnapshotTasks",
-		Delay:       request.ConstantWaiterDelay(15 * time.Second),
-		MaxAttempts: 120,
-		Acceptors: []request.WaiterAcceptor{
-			{
-				State:    request.SuccessWaiterState,
-				Matcher:  request.PathAllWaiterMatch,
-				Argument: "ImportSnapshotTasks[].SnapshotTaskDetail.Status",
-				Expected: "completed",
-			},
-			{
-				State:    request.FailureWaiterState,
-				Matcher:  request.PathAnyWaiterMatch,
-				Argument: "ImportSnapshotTasks[].SnapshotTaskDetail.Status",
-				Expected: "deleted",
-			},
-			{
-				State:    request.FailureWaiterState,
-				Matcher:  request.PathAnyWaiterMatch,
-				Argument: "ImportSnapshotTasks[].SnapshotTaskDetail.Status",
-				Expected: "deleting",
-			},
-		},
-		NewRequest: func(opts []request.Option) (*request.Request, error) {
+	err = waitForState(
+		func() (*request.Request, error) {
 			req, _ := p.ec2.DescribeImportSnapshotTasksRequest(taskFilter)
-			req.SetContext(ct)
-			req.ApplyOptions(opts...)
 			return req, nil
 		},
-	}
-	err = w.WaitWithContext(ct)
+		"ImportSnapshotTasks[].SnapshotTaskDetail.Status",
+		"completed",
+		[]string{"deleted", "deleting"},
+	)
 	if err != nil {
 		fmt.Printf("import timed out after %f minutes\n", time.Since(waitStartTime).Minutes())
 		return nil, err
@@ -370,3 +643,177 @@ func (p *AWS) waitSnapshotToBeReady(config *Config, importTaskID *string) (*stri
 
 	return snapshotID, nil
 }
+
+// waitForState polls makeRequest until argument (a JMESPath-style expression
+// evaluated against the response) matches expected, or fails fast if it
+// matches any of failStates. Shared by waitSnapshotToBeReady and AMI
+// replication, which both wait on an eventually-consistent AWS resource.
+func waitForState(makeRequest func() (*request.Request, error), argument, expected string, failStates []string) error {
+	acceptors := []request.WaiterAcceptor{
+		{
+			State:    request.SuccessWaiterState,
+			Matcher:  request.PathAllWaiterMatch,
+			Argument: argument,
+			Expected: expected,
+		},
+	}
+	for _, fs := range failStates {
+		acceptors = append(acceptors, request.WaiterAcceptor{
+			State:    request.FailureWaiterState,
+			Matcher:  request.PathAnyWaiterMatch,
+			Argument: argument,
+			Expected: fs,
+		})
+	}
+
+	ct := aws.BackgroundContext()
+	w := request.Waiter{
+		Name:        "ops-wait-for-state",
+		Delay:       request.ConstantWaiterDelay(15 * time.Second),
+		MaxAttempts: 120,
+		Acceptors:   acceptors,
+		NewRequest: func(opts []request.Option) (*request.Request, error) {
+			req, err := makeRequest()
+			if err != nil {
+				return nil, err
+			}
+			req.SetContext(ct)
+			req.ApplyOptions(opts...)
+			return req, nil
+		},
+	}
+
+	return w.WaitWithContext(ct)
+}
+
+// replicateImage copies imageID into each region in config.CloudConfig.ReplicateTo,
+// tags the copy in its destination region and waits for it to become
+// available, mirroring the multi-location publishing behavior used to
+// distribute images across regions.
+func (p *AWS) replicateImage(imageID string, config *Config, tags []*ec2.Tag) error {
+	sourceRegion := config.CloudConfig.Zone
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(config.CloudConfig.ReplicateTo))
+
+	for _, region := range config.CloudConfig.ReplicateTo {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			svc, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+			if err != nil {
+				errs <- fmt.Errorf("%s: %s", region, err)
+				return
+			}
+			compute := ec2.New(svc)
+
+			res, err := compute.CopyImage(&ec2.CopyImageInput{
+				Name:          aws.String(config.CloudConfig.ImageName),
+				SourceImageId: aws.String(imageID),
+				SourceRegion:  aws.String(sourceRegion),
+			})
+			if err != nil {
+				errs <- fmt.Errorf("%s: %s", region, err)
+				return
+			}
+
+			_, err = compute.CreateTags(&ec2.CreateTagsInput{
+				Resources: []*string{res.ImageId},
+				Tags:      tags,
+			})
+			if err != nil {
+				errs <- fmt.Errorf("%s: %s", region, err)
+				return
+			}
+
+			err = waitForState(
+				func() (*request.Request, error) {
+					req, _ := compute.DescribeImagesRequest(&ec2.DescribeImagesInput{ImageIds: []*string{res.ImageId}})
+					return req, nil
+				},
+				"Images[].State",
+				"available",
+				[]string{"failed"},
+			)
+			if err != nil {
+				errs <- fmt.Errorf("%s: %s", region, err)
+			}
+		}(region)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var failures []string
+	for err := range errs {
+		failures = append(failures, err.Error())
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("image replication failed: %s", strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// ListImagesAllRegions lists images across every AWS region, tagging each
+// row with its source region.
+func (p *AWS) ListImagesAllRegions(ctx *Context) error {
+	regionsOut, err := p.ec2.DescribeRegions(&ec2.DescribeRegionsInput{})
+	if err != nil {
+		return err
+	}
+
+	type regionalImage struct {
+		CloudImage
+		Region string
+	}
+
+	var mu sync.Mutex
+	var rows []regionalImage
+	var wg sync.WaitGroup
+
+	for _, r := range regionsOut.Regions {
+		region := aws.StringValue(r.RegionName)
+
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			svc, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+			if err != nil {
+				return
+			}
+
+			out, err := getAWSImages(ec2.New(svc))
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			for _, image := range out.Images {
+				rows = append(rows, regionalImage{
+					CloudImage: CloudImage{
+						Name:    imageName(image),
+						ID:      aws.StringValue(image.ImageId),
+						Status:  aws.StringValue(image.State),
+						Created: aws.StringValue(image.CreationDate),
+					},
+					Region: region,
+				})
+			}
+			mu.Unlock()
+		}(region)
+	}
+	wg.Wait()
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Id", "Status", "Created", "Region"})
+	table.SetRowLine(true)
+	for _, row := range rows {
+		table.Append([]string{row.Name, row.ID, row.Status, row.Created, row.Region})
+	}
+	table.Render()
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package lepton
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func taggedImage(name string, age time.Duration) *ec2.Image {
+	return &ec2.Image{
+		Tags:         []*ec2.Tag{{Key: aws.String("CreatedBy"), Value: aws.String(name)}},
+		CreationDate: aws.String(time.Now().Add(-age).Format(time.RFC3339)),
+	}
+}
+
+func TestImageMatchesFilterCombinesMatchAndOlderThan(t *testing.T) {
+	oldStaging := taggedImage("staging-1", 30*24*time.Hour)
+	newStaging := taggedImage("staging-2", time.Hour)
+	oldProd := taggedImage("prod-1", 30*24*time.Hour)
+
+	filter := ImageFilter{Match: "staging-*", OlderThan: 24 * time.Hour}
+
+	if !imageMatchesFilter(oldStaging, filter) {
+		t.Error("expected an old staging image to match --match + --older-than")
+	}
+	if imageMatchesFilter(newStaging, filter) {
+		t.Error("a recent staging image should not match --older-than even though it matches --match")
+	}
+	if imageMatchesFilter(oldProd, filter) {
+		t.Error("an old prod image should not match --match even though it matches --older-than")
+	}
+}
+
+func TestImageMatchesFilterExplicitNameBypassesOtherFilters(t *testing.T) {
+	image := taggedImage("prod-1", time.Hour)
+	filter := ImageFilter{Names: []string{"prod-1"}, Match: "staging-*", OlderThan: 24 * time.Hour}
+
+	if !imageMatchesFilter(image, filter) {
+		t.Error("an explicitly named image should match regardless of --match/--older-than")
+	}
+}
+
+func TestImageMatchesFilterAll(t *testing.T) {
+	image := taggedImage("anything", time.Hour)
+	if !imageMatchesFilter(image, ImageFilter{All: true}) {
+		t.Error("--all should match every image")
+	}
+}
+
+func TestImageMatchesFilterNoCriteria(t *testing.T) {
+	image := taggedImage("prod-1", time.Hour)
+	if imageMatchesFilter(image, ImageFilter{}) {
+		t.Error("an empty filter should not match any image")
+	}
+}
@@ -0,0 +1,43 @@
+package lepton
+
+// RunConfig captures the run-time settings for a built nanos image:
+// what it's named once booted, what klibs to link in, and the tags to
+// attach to cloud resources created for it.
+type RunConfig struct {
+	Imagename string
+	Klibs     []string
+	Tags      map[string]string
+}
+
+// CloudConfig captures everything a Provider needs to build and publish an
+// image on a specific cloud platform.
+type CloudConfig struct {
+	Platform   string
+	BucketName string
+	Zone       string
+	ImageName  string
+
+	// Architecture selects the AMI/instance architecture (ArchX86_64 or
+	// ArchARM64); empty defaults to ArchX86_64.
+	Architecture string
+
+	// SecureBoot, when set with Enabled true, registers the AMI with UEFI
+	// Secure Boot enabled using the given PK/KEK/db/dbx certificates.
+	SecureBoot *SecureBootConfig
+
+	// ReplicateTo lists additional AWS regions the registered AMI should be
+	// copied into after RegisterImage succeeds.
+	ReplicateTo []string
+}
+
+// Config is the parsed ops config file plus anything layered on top of it
+// from the command line.
+type Config struct {
+	Program      string
+	Args         []string
+	BuildDir     string
+	NightlyBuild bool
+	Env          map[string]string
+	CloudConfig  CloudConfig
+	RunConfig    RunConfig
+}
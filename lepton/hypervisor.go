@@ -10,10 +10,19 @@ func checkExists(key string) bool {
 	return true
 }
 
-func HypervisorInstance() Hypervisor {
-	for k := range hypervisors {
+// HypervisorInstance returns an available hypervisor capable of running
+// images built for arch ("x86_64" or "arm64"); an empty arch, or no arch
+// given at all, defaults to x86_64. arch is variadic so existing callers
+// built against the pre-arm64 HypervisorInstance() keep compiling.
+func HypervisorInstance(arch ...string) Hypervisor {
+	var target string
+	if len(arch) > 0 {
+		target = arch[0]
+	}
+
+	for k, newHypervisor := range hypervisorsForArch(target) {
 		if checkExists(k) {
-			hypervisor := hypervisors[k]()
+			hypervisor := newHypervisor()
 			return hypervisor
 		}
 	}
@@ -26,7 +35,19 @@ type Hypervisor interface {
 	Stop()
 }
 
-// available hypervisors
+// available hypervisors, x86_64
 var hypervisors = map[string]func() Hypervisor{
 	"qemu-system-x86_64": newQemu,
+}
+
+// available hypervisors, arm64/Graviton
+var hypervisorsARM64 = map[string]func() Hypervisor{
+	"qemu-system-aarch64": newQemu,
+}
+
+func hypervisorsForArch(arch string) map[string]func() Hypervisor {
+	if arch == ArchARM64 {
+		return hypervisorsARM64
+	}
+	return hypervisors
 }
\ No newline at end of file
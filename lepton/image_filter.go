@@ -0,0 +1,15 @@
+package lepton
+
+import "time"
+
+// ImageFilter selects the set of images a bulk operation like DeleteImages
+// should apply to: an explicit list of names, --all, a --match glob, or an
+// --older-than age cutoff. DryRun reports what would be affected without
+// making any changes.
+type ImageFilter struct {
+	Names     []string
+	All       bool
+	Match     string
+	OlderThan time.Duration
+	DryRun    bool
+}
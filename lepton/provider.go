@@ -0,0 +1,39 @@
+package lepton
+
+import "errors"
+
+// ErrDeleteImagesUnsupported reports that a provider has no DeleteImages
+// implementation. No provider in this package returns it; AWS implements
+// DeleteImages.
+var ErrDeleteImagesUnsupported = errors.New("DeleteImages is not supported by this provider")
+
+// Provider is implemented by every supported cloud/onprem backend (AWS,
+// GCP, Azure, DO, Vultr, OnPrem) so the image/instance commands can drive
+// them interchangeably.
+type Provider interface {
+	BuildImage(ctx *Context) (string, error)
+	BuildImageWithPackage(ctx *Context, pkgpath string) (string, error)
+	CreateImage(ctx *Context, imagePath string) error
+	CustomizeImage(ctx *Context) (string, error)
+
+	GetImages(ctx *Context) ([]CloudImage, error)
+	ListImages(ctx *Context) error
+	ResizeImage(ctx *Context, imagename string, hbytes string) error
+	DeleteImage(ctx *Context, imagename string) error
+
+	// DeleteImages deletes every image matching filter, best-effort
+	// cleaning up any backing storage it owns. A failure on one image
+	// must not abort the rest of the batch.
+	DeleteImages(ctx *Context, filter ImageFilter) ([]string, error)
+
+	SyncImage(config *Config, target Provider, image string) error
+}
+
+// CloudImage is a provider-agnostic view of a registered image, as shown by
+// `ops image list`.
+type CloudImage struct {
+	Name    string
+	ID      string
+	Status  string
+	Created string
+}
@@ -0,0 +1,154 @@
+package lepton
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+)
+
+// SecureBootConfig holds the certificates/keys used to assemble the UEFI
+// variable store registered alongside an AMI so Nitro instances can enforce
+// Secure Boot.
+type SecureBootConfig struct {
+	Enabled bool
+	PK      string
+	KEK     string
+	DB      string
+	DBX     string
+	// UefiData is a pre-built base64 EFI variable store (e.g. exported from
+	// a reference instance with AWS's own tooling); when set it takes
+	// precedence over PK/KEK/DB/DBX.
+	UefiData string
+}
+
+// efiCertX509GUID is EFI_CERT_X509_GUID from the UEFI spec
+// (a5c059a1-94e4-4aa7-87b5-ab155c2bf072), encoded the way an EFI_GUID is
+// laid out on disk (first three fields little-endian).
+var efiCertX509GUID = [16]byte{
+	0xa1, 0x59, 0xc0, 0xa5, 0xe4, 0x94, 0xa7, 0x4a,
+	0x87, 0xb5, 0xab, 0x15, 0x5c, 0x2b, 0xf0, 0x72,
+}
+
+// efiSignatureOwnerGUID tags every EFI_SIGNATURE_DATA entry we emit with a
+// single owner GUID; firmware only requires it to be consistent within a
+// list, not that it identify a real registered owner.
+var efiSignatureOwnerGUID [16]byte
+
+// efiSignatureListHeader mirrors the on-disk layout of EFI_SIGNATURE_LIST
+// (UEFI spec, "Signature Database"): a signature type GUID, the overall
+// list/header/signature sizes, SignatureHeaderSize bytes of header (unused
+// for EFI_CERT_X509_GUID) and one or more EFI_SIGNATURE_DATA entries.
+type efiSignatureListHeader struct {
+	SignatureType       [16]byte
+	SignatureListSize   uint32
+	SignatureHeaderSize uint32
+	SignatureSize       uint32
+}
+
+// buildSignatureList reads every "CERTIFICATE" PEM block out of path and
+// encodes them as a single EFI_SIGNATURE_LIST, the format UEFI firmware (and
+// AWS's Secure Boot AMI registration) expects for the PK/KEK/db/dbx
+// variables. dbx commonly holds several revoked certificates, which this
+// also supports as long as they're all the same size.
+func buildSignatureList(path string) ([]byte, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs [][]byte
+	rest := raw
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		certs = append(certs, block.Bytes)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s: no PEM certificate found", path)
+	}
+
+	sigSize := uint32(16 + len(certs[0]))
+	for _, c := range certs {
+		if uint32(16+len(c)) != sigSize {
+			return nil, fmt.Errorf("%s: all certificates in one signature list must be the same size", path)
+		}
+	}
+
+	header := efiSignatureListHeader{
+		SignatureType:     efiCertX509GUID,
+		SignatureSize:     sigSize,
+		SignatureListSize: uint32(binary.Size(efiSignatureListHeader{})) + sigSize*uint32(len(certs)),
+	}
+
+	buf := &bytes.Buffer{}
+	if err := binary.Write(buf, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+	for _, c := range certs {
+		buf.Write(efiSignatureOwnerGUID[:])
+		buf.Write(c)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// buildUefiData assembles a base64-encoded blob containing the PK, KEK and
+// db EFI_SIGNATURE_LISTs, plus dbx when sb.DBX is set, suitable for
+// ec2.RegisterImageInput.UefiData.
+// Each variable is stored as a length-prefixed name followed by a
+// length-prefixed EFI_SIGNATURE_LIST, so the blob can be decoded back into
+// its constituent variables without external tooling.
+func buildUefiData(sb *SecureBootConfig) (string, error) {
+	if sb.UefiData != "" {
+		return sb.UefiData, nil
+	}
+
+	vars := []struct{ name, path string }{
+		{"PK", sb.PK},
+		{"KEK", sb.KEK},
+		{"db", sb.DB},
+	}
+	if sb.DBX != "" {
+		vars = append(vars, struct{ name, path string }{"dbx", sb.DBX})
+	}
+
+	buf := &bytes.Buffer{}
+	for _, v := range vars {
+		list, err := buildSignatureList(v.path)
+		if err != nil {
+			return "", err
+		}
+
+		if err := writeUefiVar(buf, v.name, list); err != nil {
+			return "", err
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func writeUefiVar(buf *bytes.Buffer, name string, list []byte) error {
+	if err := binary.Write(buf, binary.LittleEndian, uint32(len(name))); err != nil {
+		return err
+	}
+	buf.WriteString(name)
+
+	if err := binary.Write(buf, binary.LittleEndian, uint32(len(list))); err != nil {
+		return err
+	}
+	buf.Write(list)
+	return nil
+}
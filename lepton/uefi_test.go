@@ -0,0 +1,172 @@
+package lepton
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCertPEM writes a throwaway self-signed certificate to path
+// and returns its DER bytes for comparison against the decoded output.
+func writeSelfSignedCertPEM(t *testing.T, path, commonName string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %s", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %s", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %s", path, err)
+	}
+	defer f.Close()
+
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("encode %s: %s", path, err)
+	}
+
+	return der
+}
+
+// readUefiVars decodes the buildUefiData container format back into its
+// name -> EFI_SIGNATURE_LIST entries, mirroring what consuming tooling
+// would have to do.
+func readUefiVars(t *testing.T, raw []byte) map[string][]byte {
+	t.Helper()
+
+	vars := map[string][]byte{}
+	r := bytes.NewReader(raw)
+	for r.Len() > 0 {
+		var nameLen uint32
+		if err := binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+			t.Fatalf("read name length: %s", err)
+		}
+		name := make([]byte, nameLen)
+		if _, err := r.Read(name); err != nil {
+			t.Fatalf("read name: %s", err)
+		}
+
+		var listLen uint32
+		if err := binary.Read(r, binary.LittleEndian, &listLen); err != nil {
+			t.Fatalf("read list length: %s", err)
+		}
+		list := make([]byte, listLen)
+		if _, err := r.Read(list); err != nil {
+			t.Fatalf("read list: %s", err)
+		}
+
+		vars[string(name)] = list
+	}
+	return vars
+}
+
+func TestBuildUefiDataProducesDecodableSignatureLists(t *testing.T) {
+	dir := t.TempDir()
+	pkDER := writeSelfSignedCertPEM(t, filepath.Join(dir, "pk.pem"), "pk")
+	kekDER := writeSelfSignedCertPEM(t, filepath.Join(dir, "kek.pem"), "kek")
+	dbDER := writeSelfSignedCertPEM(t, filepath.Join(dir, "db.pem"), "db")
+
+	sb := &SecureBootConfig{
+		Enabled: true,
+		PK:      filepath.Join(dir, "pk.pem"),
+		KEK:     filepath.Join(dir, "kek.pem"),
+		DB:      filepath.Join(dir, "db.pem"),
+	}
+
+	encoded, err := buildUefiData(sb)
+	if err != nil {
+		t.Fatalf("buildUefiData: %s", err)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("buildUefiData did not return valid base64: %s", err)
+	}
+
+	vars := readUefiVars(t, raw)
+	for name, der := range map[string][]byte{"PK": pkDER, "KEK": kekDER, "db": dbDER} {
+		list, ok := vars[name]
+		if !ok {
+			t.Fatalf("missing %s variable in output", name)
+		}
+		assertSignatureList(t, list, der)
+	}
+	if _, ok := vars["dbx"]; ok {
+		t.Fatal("dbx should be absent when SecureBootConfig.DBX is unset")
+	}
+}
+
+func TestBuildUefiDataPrefersPreBuiltBlob(t *testing.T) {
+	sb := &SecureBootConfig{Enabled: true, UefiData: "cHJlYnVpbHQ="}
+	got, err := buildUefiData(sb)
+	if err != nil {
+		t.Fatalf("buildUefiData: %s", err)
+	}
+	if got != sb.UefiData {
+		t.Fatalf("expected pre-built UefiData to be returned as-is, got %q", got)
+	}
+}
+
+// assertSignatureList decodes an EFI_SIGNATURE_LIST and checks its header
+// fields and embedded certificate against what a real AWSUEFI NVRAM
+// template carries for a single-certificate PK/KEK/db variable: the
+// EFI_CERT_X509_GUID signature type, a signature size of 16 (owner GUID) +
+// len(cert), and the certificate's raw DER bytes verbatim.
+func assertSignatureList(t *testing.T, list []byte, wantDER []byte) {
+	t.Helper()
+
+	var header efiSignatureListHeader
+	r := bytes.NewReader(list)
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		t.Fatalf("read EFI_SIGNATURE_LIST header: %s", err)
+	}
+
+	if header.SignatureType != efiCertX509GUID {
+		t.Fatalf("SignatureType = %x, want EFI_CERT_X509_GUID %x", header.SignatureType, efiCertX509GUID)
+	}
+
+	wantSigSize := uint32(16 + len(wantDER))
+	if header.SignatureSize != wantSigSize {
+		t.Fatalf("SignatureSize = %d, want %d", header.SignatureSize, wantSigSize)
+	}
+
+	wantListSize := uint32(binary.Size(efiSignatureListHeader{})) + wantSigSize
+	if header.SignatureListSize != wantListSize {
+		t.Fatalf("SignatureListSize = %d, want %d", header.SignatureListSize, wantListSize)
+	}
+
+	gotDER, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read signature data: %s", err)
+	}
+	gotDER = gotDER[16:] // skip the owner GUID
+
+	if !bytes.Equal(gotDER, wantDER) {
+		t.Fatal("embedded certificate does not match the source DER bytes")
+	}
+}
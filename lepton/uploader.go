@@ -0,0 +1,158 @@
+package lepton
+
+import (
+	"context"
+	"time"
+)
+
+// uploadRetries and uploadRetryDelay bound the retry/backoff applied to
+// the archive, upload and cleanup stages of a staged Uploader, so a
+// transient API error doesn't fail an otherwise healthy upload. Import and
+// Register are not retried by the Uploader.
+const (
+	uploadRetries    = 3
+	uploadRetryDelay = time.Second
+)
+
+// Logger is the minimal progress-reporting surface a staged Uploader
+// reports through. Context.logger satisfies it, so callers can pass
+// ctx.logger straight into UploadRequest.
+type Logger interface {
+	Info(format string, args ...interface{})
+	Log(msg string)
+}
+
+// UploadRequest describes the local artifact being handed off to an
+// Uploader, plus the config driving the pipeline and an optional Logger
+// for progress reporting.
+type UploadRequest struct {
+	ImagePath string
+	Config    *Config
+	Logger    Logger
+}
+
+// Uploader drives a local nanos image through the archive -> blob store ->
+// disk import -> registration stages required to make it bootable on a
+// cloud provider. A new cloud only has to implement Archiver, BlobStore,
+// DiskImporter and ImageRegistrar and can be wired up with newStagedUploader,
+// instead of writing a monolithic CreateImage.
+type Uploader interface {
+	Upload(ctx context.Context, req UploadRequest) (map[string]string, error)
+}
+
+// Archiver converts a raw nanos image into the disk format a given cloud
+// expects (raw, vhd, tar.gz, ...).
+type Archiver interface {
+	Archive(ctx context.Context, imagePath string) (string, error)
+}
+
+// BlobStore puts and removes an archived image from the cloud's object
+// store (S3/GCS/Azure Blob).
+type BlobStore interface {
+	Put(ctx context.Context, key, path string) error
+	Delete(ctx context.Context, key string) error
+}
+
+// DiskImporter turns a blob in object storage into a bootable disk/snapshot
+// (ImportSnapshot / GCE image insert / Azure disk create) and returns its ID.
+type DiskImporter interface {
+	Import(ctx context.Context, key string) (string, error)
+}
+
+// ImageRegistrar registers an imported disk as a bootable image and returns
+// its ID.
+type ImageRegistrar interface {
+	Register(ctx context.Context, diskID string) (string, error)
+}
+
+// stagedUploader composes an Archiver, BlobStore, DiskImporter and
+// ImageRegistrar into a single Uploader.
+type stagedUploader struct {
+	archiver  Archiver
+	blobStore BlobStore
+	importer  DiskImporter
+	registrar ImageRegistrar
+}
+
+func newStagedUploader(archiver Archiver, blobStore BlobStore, importer DiskImporter, registrar ImageRegistrar) Uploader {
+	return &stagedUploader{archiver, blobStore, importer, registrar}
+}
+
+func (u *stagedUploader) Upload(ctx context.Context, req UploadRequest) (map[string]string, error) {
+	logger := req.Logger
+	key := req.Config.CloudConfig.ImageName
+
+	var archivePath string
+	err := withRetry(logger, "archive", func() (err error) {
+		archivePath, err = u.archiver.Archive(ctx, req.ImagePath)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	logInfo(logger, "Archived image as %s", archivePath)
+
+	if err := withRetry(logger, "upload", func() error {
+		return u.blobStore.Put(ctx, key, archivePath)
+	}); err != nil {
+		return nil, err
+	}
+	logInfo(logger, "Uploaded %s to blob store", key)
+
+	// Import is not retried here: for AWS it submits a non-idempotent
+	// ImportSnapshot call, so retrying it at this level would leak a new
+	// snapshot/import task per attempt. Implementations retry whatever
+	// sub-steps of their own are actually safe to retry internally.
+	diskID, err := u.importer.Import(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	logInfo(logger, "Imported disk %s", diskID)
+
+	// Blob cleanup is best-effort: the disk is already imported either way,
+	// so a failure here shouldn't abort the upload. Retried like the
+	// archive and upload stages since it's a plain idempotent delete.
+	if err := withRetry(logger, "cleanup", func() error {
+		return u.blobStore.Delete(ctx, key)
+	}); err != nil {
+		logInfo(logger, "Failed to clean up %s from blob store: %s", key, err)
+	}
+
+	// Register is not retried: it creates a new, uniquely-named image on
+	// every call, so retrying after a partial failure (e.g. tagging the
+	// registered image) would register and tag a second image instead of
+	// resuming the first.
+	imageID, err := u.registrar.Register(ctx, diskID)
+	if err != nil {
+		return nil, err
+	}
+	logInfo(logger, "Registered image %s", imageID)
+
+	return map[string]string{"imageId": imageID, "diskId": diskID}, nil
+}
+
+// withRetry runs fn up to uploadRetries times with a fixed backoff between
+// attempts, returning the last error if every attempt fails. Each failed
+// attempt is reported through logger so a slow-but-eventually-healthy stage
+// doesn't look like a silent hang.
+func withRetry(logger Logger, step string, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= uploadRetries; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		logInfo(logger, "%s failed (attempt %d/%d): %s", step, attempt, uploadRetries, err)
+
+		if attempt < uploadRetries {
+			time.Sleep(uploadRetryDelay)
+		}
+	}
+	return err
+}
+
+func logInfo(logger Logger, format string, args ...interface{}) {
+	if logger != nil {
+		logger.Info(format, args...)
+	}
+}
@@ -0,0 +1,131 @@
+package lepton
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type mockArchiver struct {
+	path string
+	err  error
+}
+
+func (m *mockArchiver) Archive(ctx context.Context, imagePath string) (string, error) {
+	return m.path, m.err
+}
+
+type mockBlobStore struct {
+	putCalls  int
+	failPutsN int
+	deleted   bool
+	deleteErr error
+}
+
+func (m *mockBlobStore) Put(ctx context.Context, key, path string) error {
+	m.putCalls++
+	if m.putCalls <= m.failPutsN {
+		return errors.New("put failed")
+	}
+	return nil
+}
+
+func (m *mockBlobStore) Delete(ctx context.Context, key string) error {
+	m.deleted = true
+	return m.deleteErr
+}
+
+type mockDiskImporter struct {
+	diskID string
+	err    error
+}
+
+func (m *mockDiskImporter) Import(ctx context.Context, key string) (string, error) {
+	return m.diskID, m.err
+}
+
+type mockRegistrar struct {
+	imageID string
+	err     error
+}
+
+func (m *mockRegistrar) Register(ctx context.Context, diskID string) (string, error) {
+	return m.imageID, m.err
+}
+
+func TestStagedUploaderUploadSuccess(t *testing.T) {
+	blobStore := &mockBlobStore{}
+	uploader := newStagedUploader(
+		&mockArchiver{path: "/tmp/image.raw"},
+		blobStore,
+		&mockDiskImporter{diskID: "disk-1"},
+		&mockRegistrar{imageID: "image-1"},
+	)
+
+	req := UploadRequest{ImagePath: "/tmp/image.raw", Config: &Config{CloudConfig: CloudConfig{ImageName: "my-image"}}}
+	result, err := uploader.Upload(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result["diskId"] != "disk-1" || result["imageId"] != "image-1" {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	if !blobStore.deleted {
+		t.Fatal("expected blob store object to be cleaned up after import")
+	}
+}
+
+func TestStagedUploaderRetriesTransientFailures(t *testing.T) {
+	blobStore := &mockBlobStore{failPutsN: 2}
+	uploader := newStagedUploader(
+		&mockArchiver{path: "/tmp/image.raw"},
+		blobStore,
+		&mockDiskImporter{diskID: "disk-1"},
+		&mockRegistrar{imageID: "image-1"},
+	)
+
+	req := UploadRequest{ImagePath: "/tmp/image.raw", Config: &Config{CloudConfig: CloudConfig{ImageName: "my-image"}}}
+	if _, err := uploader.Upload(context.Background(), req); err != nil {
+		t.Fatalf("expected upload to succeed after retrying, got: %s", err)
+	}
+	if blobStore.putCalls != 3 {
+		t.Fatalf("expected 3 put attempts, got %d", blobStore.putCalls)
+	}
+}
+
+func TestStagedUploaderGivesUpAfterExhaustingRetries(t *testing.T) {
+	blobStore := &mockBlobStore{failPutsN: uploadRetries}
+	uploader := newStagedUploader(
+		&mockArchiver{path: "/tmp/image.raw"},
+		blobStore,
+		&mockDiskImporter{diskID: "disk-1"},
+		&mockRegistrar{imageID: "image-1"},
+	)
+
+	req := UploadRequest{ImagePath: "/tmp/image.raw", Config: &Config{CloudConfig: CloudConfig{ImageName: "my-image"}}}
+	if _, err := uploader.Upload(context.Background(), req); err == nil {
+		t.Fatal("expected upload to fail once retries are exhausted")
+	}
+	if blobStore.putCalls != uploadRetries {
+		t.Fatalf("expected %d put attempts, got %d", uploadRetries, blobStore.putCalls)
+	}
+}
+
+func TestStagedUploaderStopsOnArchiveFailure(t *testing.T) {
+	blobStore := &mockBlobStore{}
+	uploader := newStagedUploader(
+		&mockArchiver{err: errors.New("archive failed")},
+		blobStore,
+		&mockDiskImporter{diskID: "disk-1"},
+		&mockRegistrar{imageID: "image-1"},
+	)
+
+	req := UploadRequest{ImagePath: "/tmp/image.raw", Config: &Config{CloudConfig: CloudConfig{ImageName: "my-image"}}}
+	if _, err := uploader.Upload(context.Background(), req); err == nil {
+		t.Fatal("expected upload to fail when the archive stage errors")
+	}
+	if blobStore.putCalls != 0 {
+		t.Fatal("blob store should not be touched when archiving fails")
+	}
+}